@@ -0,0 +1,292 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package acme issues and renews TLS certificates through the ACME protocol, as an
+// alternative to Google-managed certificates for clusters that cannot use them (private
+// CAs, non-GCLB load balancing, or custom challenge flows). Issued certificates are meant
+// to be uploaded to GCE as SELF_MANAGED SslCertificate resources through pkg/clients/ssl.
+package acme
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"time"
+
+	"golang.org/x/crypto/acme"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog"
+)
+
+const (
+	// secretKeyAccountKey is the data key under which the ACME account private key is
+	// persisted in the account Secret, as a PEM-encoded SEC 1 ("EC PRIVATE KEY") block.
+	secretKeyAccountKey = "acme-account-key.pem"
+
+	// defaultRenewBefore is how long before expiry a certificate is renewed, if
+	// NewRenewer is given a zero renewBefore (or Config does not specify RenewBefore).
+	defaultRenewBefore = 30 * 24 * time.Hour
+)
+
+// Challenge identifies which ACME challenge type a Solver handles.
+type Challenge string
+
+const (
+	ChallengeHTTP01 Challenge = "http01"
+	ChallengeDNS01  Challenge = "dns01"
+)
+
+// Solver completes an ACME authorization challenge for a single domain and cleans up
+// after itself once the authorization has been validated.
+type Solver interface {
+	// Present makes the key authorization for token available so the ACME server can
+	// validate domain.
+	Present(ctx context.Context, domain, token, keyAuth string) error
+
+	// CleanUp removes whatever Present set up for domain.
+	CleanUp(ctx context.Context, domain, token, keyAuth string) error
+}
+
+// Issuer obtains PEM-encoded certificate and private key pairs from an ACME CA.
+type Issuer interface {
+	// Issue requests a certificate covering domains and returns the leaf certificate
+	// chain and private key, both PEM-encoded, along with the certificate's expiry.
+	Issue(ctx context.Context, domains []string) (certPEM, keyPEM string, notAfter time.Time, err error)
+}
+
+// Config configures an Issuer.
+type Config struct {
+	// DirectoryURL is the ACME server directory endpoint, e.g.
+	// "https://acme-v02.api.letsencrypt.org/directory".
+	DirectoryURL string
+
+	// Email is the contact address associated with the ACME account.
+	Email string
+
+	// Challenge selects which Solver Issue uses to complete authorizations.
+	Challenge Challenge
+
+	// Solver completes authorizations for Challenge.
+	Solver Solver
+
+	// RenewBefore is how long before expiry a certificate is considered due for
+	// renewal. Defaults to 30 days. The Issuer only stores it; Issue does not read it, and
+	// the threshold a Renewer applies is the renewBefore passed to NewRenewer.
+	RenewBefore time.Duration
+
+	// AccountSecretNamespace and AccountSecretName locate the Secret used to persist
+	// the ACME account private key across restarts.
+	AccountSecretNamespace string
+	AccountSecretName      string
+}
+
+type issuerImpl struct {
+	client      *acme.Client
+	account     *acme.Account
+	challenge   Challenge
+	solver      Solver
+	renewBefore time.Duration
+}
+
+// New registers (or loads) an ACME account and returns an Issuer that completes
+// authorizations with cfg.Solver.
+func New(ctx context.Context, clientset kubernetes.Interface, cfg Config) (Issuer, error) {
+	accountKey, err := loadOrCreateAccountKey(ctx, clientset, cfg.AccountSecretNamespace, cfg.AccountSecretName)
+	if err != nil {
+		return nil, fmt.Errorf("could not load ACME account key: %s", err.Error())
+	}
+
+	client := &acme.Client{
+		Key:          accountKey,
+		DirectoryURL: cfg.DirectoryURL,
+	}
+
+	account, err := client.Register(ctx, &acme.Account{Contact: []string{"mailto:" + cfg.Email}}, acme.AcceptTOS)
+	if err != nil && err != acme.ErrAccountAlreadyExists {
+		return nil, fmt.Errorf("could not register ACME account: %s", err.Error())
+	}
+
+	renewBefore := cfg.RenewBefore
+	if renewBefore == 0 {
+		renewBefore = defaultRenewBefore
+	}
+
+	return &issuerImpl{
+		client:      client,
+		account:     account,
+		challenge:   cfg.Challenge,
+		solver:      cfg.Solver,
+		renewBefore: renewBefore,
+	}, nil
+}
+
+// Issue requests a certificate for domains, completing an authorization for each domain
+// via the configured Solver, and returns the resulting PEM-encoded certificate chain and
+// private key.
+func (i *issuerImpl) Issue(ctx context.Context, domains []string) (string, string, time.Time, error) {
+	if len(domains) == 0 {
+		return "", "", time.Time{}, fmt.Errorf("no domains to issue a certificate for")
+	}
+
+	order, err := i.client.AuthorizeOrder(ctx, acme.DomainIDs(domains...))
+	if err != nil {
+		return "", "", time.Time{}, fmt.Errorf("could not authorize order: %w", err)
+	}
+
+	for _, authzURL := range order.AuthzURLs {
+		if err := i.authorize(ctx, authzURL); err != nil {
+			return "", "", time.Time{}, err
+		}
+	}
+
+	leafKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return "", "", time.Time{}, fmt.Errorf("could not generate leaf key: %s", err.Error())
+	}
+
+	csr, err := newCSR(leafKey, domains)
+	if err != nil {
+		return "", "", time.Time{}, fmt.Errorf("could not create CSR: %s", err.Error())
+	}
+
+	der, _, err := i.client.CreateOrderCert(ctx, order.FinalizeURL, csr, true)
+	if err != nil {
+		return "", "", time.Time{}, fmt.Errorf("could not finalize order: %w", err)
+	}
+
+	certPEM, err := encodeCertChain(der)
+	if err != nil {
+		return "", "", time.Time{}, err
+	}
+
+	keyPEM, err := encodeECPrivateKey(leafKey)
+	if err != nil {
+		return "", "", time.Time{}, err
+	}
+
+	leaf, err := x509.ParseCertificate(der[0])
+	if err != nil {
+		return "", "", time.Time{}, fmt.Errorf("could not parse issued certificate: %s", err.Error())
+	}
+
+	return certPEM, keyPEM, leaf.NotAfter, nil
+}
+
+// authorize drives a single authorization through its challenge until it is valid.
+func (i *issuerImpl) authorize(ctx context.Context, authzURL string) error {
+	authz, err := i.client.GetAuthorization(ctx, authzURL)
+	if err != nil {
+		return fmt.Errorf("could not get authorization %s: %w", authzURL, err)
+	}
+
+	if authz.Status == acme.StatusValid {
+		return nil
+	}
+
+	chal, err := pickChallenge(authz, i.challenge)
+	if err != nil {
+		return err
+	}
+
+	var keyAuth string
+	if i.challenge == ChallengeDNS01 {
+		keyAuth, err = i.client.DNS01ChallengeRecord(chal.Token)
+	} else {
+		keyAuth, err = i.client.HTTP01ChallengeResponse(chal.Token)
+	}
+	if err != nil {
+		return fmt.Errorf("could not compute key authorization: %s", err.Error())
+	}
+
+	if err := i.solver.Present(ctx, authz.Identifier.Value, chal.Token, keyAuth); err != nil {
+		return fmt.Errorf("could not present challenge for %s: %s", authz.Identifier.Value, err.Error())
+	}
+	defer func() {
+		if err := i.solver.CleanUp(ctx, authz.Identifier.Value, chal.Token, keyAuth); err != nil {
+			klog.Warningf("Could not clean up challenge for %s: %s", authz.Identifier.Value, err.Error())
+		}
+	}()
+
+	if _, err := i.client.Accept(ctx, chal); err != nil {
+		return fmt.Errorf("could not accept challenge for %s: %w", authz.Identifier.Value, err)
+	}
+
+	if _, err := i.client.WaitAuthorization(ctx, authzURL); err != nil {
+		return fmt.Errorf("authorization %s did not become valid: %w", authz.Identifier.Value, err)
+	}
+
+	return nil
+}
+
+func pickChallenge(authz *acme.Authorization, challenge Challenge) (*acme.Challenge, error) {
+	acmeType := "http-01"
+	if challenge == ChallengeDNS01 {
+		acmeType = "dns-01"
+	}
+
+	for _, chal := range authz.Challenges {
+		if chal.Type == acmeType {
+			return chal, nil
+		}
+	}
+
+	return nil, fmt.Errorf("authorization for %s offers no %s challenge", authz.Identifier.Value, acmeType)
+}
+
+func loadOrCreateAccountKey(ctx context.Context, clientset kubernetes.Interface, namespace, name string) (*ecdsa.PrivateKey, error) {
+	secret, err := clientset.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err == nil {
+		block, _ := pem.Decode(secret.Data[secretKeyAccountKey])
+		if block == nil {
+			return nil, fmt.Errorf("secret %s/%s key %s is not valid PEM", namespace, name, secretKeyAccountKey)
+		}
+		key, err := x509.ParseECPrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return key, nil
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})
+
+	newSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      name,
+		},
+		Data: map[string][]byte{secretKeyAccountKey: keyPEM},
+	}
+	if _, err := clientset.CoreV1().Secrets(namespace).Create(ctx, newSecret, metav1.CreateOptions{}); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
@@ -0,0 +1,65 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package acme
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"strings"
+)
+
+// newCSR builds a PKCS#10 certificate request for domains, signed by key. The first
+// domain is used as the CSR's CommonName, matching how the CA will parse SANs regardless.
+func newCSR(key *ecdsa.PrivateKey, domains []string) ([]byte, error) {
+	if len(domains) == 0 {
+		return nil, fmt.Errorf("no domains to build a CSR for")
+	}
+
+	template := &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: domains[0]},
+		DNSNames: domains,
+	}
+
+	return x509.CreateCertificateRequest(rand.Reader, template, key)
+}
+
+// encodeCertChain PEM-encodes a leaf certificate followed by its issuers, in the order
+// returned by the ACME server.
+func encodeCertChain(der [][]byte) (string, error) {
+	var sb strings.Builder
+	for _, cert := range der {
+		if err := pem.Encode(&sb, &pem.Block{Type: "CERTIFICATE", Bytes: cert}); err != nil {
+			return "", fmt.Errorf("could not PEM-encode certificate: %s", err.Error())
+		}
+	}
+
+	return sb.String(), nil
+}
+
+// encodeECPrivateKey PEM-encodes an EC private key.
+func encodeECPrivateKey(key *ecdsa.PrivateKey) (string, error) {
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return "", fmt.Errorf("could not marshal private key: %s", err.Error())
+	}
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})), nil
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package acme
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	dns "google.golang.org/api/dns/v1"
+)
+
+const dns01RecordPrefix = "_acme-challenge."
+
+// DNS01Solver completes DNS-01 challenges by creating and removing TXT records in a Cloud
+// DNS managed zone.
+type DNS01Solver struct {
+	service     *dns.Service
+	projectID   string
+	managedZone string
+}
+
+// NewDNS01Solver returns a DNS01Solver that manages TXT records in managedZone, within
+// projectID, using client for authentication.
+func NewDNS01Solver(client *http.Client, projectID, managedZone string) (*DNS01Solver, error) {
+	service, err := dns.New(client)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DNS01Solver{
+		service:     service,
+		projectID:   projectID,
+		managedZone: managedZone,
+	}, nil
+}
+
+// Present creates a TXT record for _acme-challenge.domain containing keyAuth as given. The
+// Issuer passes the DNS-01 record value (the key authorization digest) as keyAuth.
+func (d *DNS01Solver) Present(ctx context.Context, domain, token, keyAuth string) error {
+	change := &dns.Change{
+		Additions: []*dns.ResourceRecordSet{recordSet(domain, keyAuth)},
+	}
+
+	_, err := d.service.Changes.Create(d.projectID, d.managedZone, change).Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("could not create TXT record for %s: %s", domain, err.Error())
+	}
+
+	return nil
+}
+
+// CleanUp removes the TXT record created by Present.
+func (d *DNS01Solver) CleanUp(ctx context.Context, domain, token, keyAuth string) error {
+	change := &dns.Change{
+		Deletions: []*dns.ResourceRecordSet{recordSet(domain, keyAuth)},
+	}
+
+	_, err := d.service.Changes.Create(d.projectID, d.managedZone, change).Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("could not delete TXT record for %s: %s", domain, err.Error())
+	}
+
+	return nil
+}
+
+func recordSet(domain, keyAuth string) *dns.ResourceRecordSet {
+	return &dns.ResourceRecordSet{
+		Name:    dns01RecordPrefix + strings.TrimSuffix(domain, ".") + ".",
+		Type:    "TXT",
+		Ttl:     60,
+		Rrdatas: []string{fmt.Sprintf("%q", keyAuth)},
+	}
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package acme
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+const wellKnownPath = "/.well-known/acme-challenge/"
+
+// HTTP01Solver serves key authorizations under /.well-known/acme-challenge/ so they can be
+// fetched by the ACME server through an ingress-attached backend pointed at it.
+type HTTP01Solver struct {
+	mu     sync.RWMutex
+	tokens map[string]string
+}
+
+// NewHTTP01Solver returns an empty HTTP01Solver. Register it as a Service backend behind
+// the Ingress being secured so the ACME server can reach it on port 80.
+func NewHTTP01Solver() *HTTP01Solver {
+	return &HTTP01Solver{tokens: make(map[string]string)}
+}
+
+// Present makes keyAuth available at /.well-known/acme-challenge/token.
+func (h *HTTP01Solver) Present(ctx context.Context, domain, token, keyAuth string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.tokens[token] = keyAuth
+	return nil
+}
+
+// CleanUp removes the key authorization for token.
+func (h *HTTP01Solver) CleanUp(ctx context.Context, domain, token, keyAuth string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delete(h.tokens, token)
+	return nil
+}
+
+// ServeHTTP responds to GET /.well-known/acme-challenge/<token> with the matching key
+// authorization, so the Ingress backend wired to this Solver can answer HTTP-01 probes.
+func (h *HTTP01Solver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.URL.Path, wellKnownPath) {
+		http.NotFound(w, r)
+		return
+	}
+	token := strings.TrimPrefix(r.URL.Path, wellKnownPath)
+
+	h.mu.RLock()
+	keyAuth, ok := h.tokens[token]
+	h.mu.RUnlock()
+
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	fmt.Fprint(w, keyAuth)
+}
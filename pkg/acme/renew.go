@@ -0,0 +1,122 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package acme
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"golang.org/x/crypto/acme"
+	"k8s.io/klog"
+)
+
+// acmeRateLimited is the ACME problem document type used by CAs (e.g. Let's Encrypt) to
+// signal that the request was throttled, as opposed to rejected outright.
+const acmeRateLimited = "urn:ietf:params:acme:error:rateLimited"
+
+// OrderStatus reports the outcome of the most recent Issue attempt for a name, meant to be
+// surfaced as a ManagedCertificate status condition.
+type OrderStatus struct {
+	Pending bool
+	Message string
+}
+
+// Certificate is the result of a successful Issue, kept by Renewer to decide when to
+// re-issue.
+type Certificate struct {
+	CertPEM  string
+	KeyPEM   string
+	NotAfter time.Time
+}
+
+// Install is called by Renewer whenever name has been (re-)issued, so the caller can push
+// the new cert/key into a SELF_MANAGED SslCertificate via pkg/clients/ssl.
+type Install func(ctx context.Context, name string, cert Certificate) error
+
+// Renewer periodically re-issues certificates that are within renewBefore of expiring.
+type Renewer struct {
+	issuer      Issuer
+	install     Install
+	renewBefore time.Duration
+
+	certs    map[string]Certificate
+	statuses map[string]OrderStatus
+}
+
+// NewRenewer returns a Renewer that uses issuer to (re-)issue certificates and install to
+// publish them, re-issuing renewBefore before a certificate's expiry.
+func NewRenewer(issuer Issuer, install Install, renewBefore time.Duration) *Renewer {
+	if renewBefore == 0 {
+		renewBefore = defaultRenewBefore
+	}
+
+	return &Renewer{
+		issuer:      issuer,
+		install:     install,
+		renewBefore: renewBefore,
+		certs:       make(map[string]Certificate),
+		statuses:    make(map[string]OrderStatus),
+	}
+}
+
+// Status returns the most recently observed order status for name.
+func (r *Renewer) Status(name string) OrderStatus {
+	return r.statuses[name]
+}
+
+// Ensure issues a certificate for name and domains if none is tracked yet, or re-issues it
+// if the tracked one is within renewBefore of expiry.
+func (r *Renewer) Ensure(ctx context.Context, name string, domains []string) error {
+	if cert, ok := r.certs[name]; ok && time.Until(cert.NotAfter) > r.renewBefore {
+		return nil
+	}
+
+	r.statuses[name] = OrderStatus{Pending: true, Message: "ACME order in progress"}
+
+	certPEM, keyPEM, notAfter, err := r.issuer.Issue(ctx, domains)
+	if err != nil {
+		r.statuses[name] = OrderStatus{Pending: false, Message: err.Error()}
+		if isRateLimited(err) {
+			klog.Warningf("ACME CA rate limited issuance for %s, will retry on next sync: %s", name, err.Error())
+			return nil
+		}
+		return err
+	}
+
+	cert := Certificate{CertPEM: certPEM, KeyPEM: keyPEM, NotAfter: notAfter}
+	if err := r.install(ctx, name, cert); err != nil {
+		r.statuses[name] = OrderStatus{Pending: false, Message: err.Error()}
+		return err
+	}
+
+	r.certs[name] = cert
+	r.statuses[name] = OrderStatus{Pending: false, Message: "Certificate issued"}
+
+	return nil
+}
+
+// isRateLimited reports whether err represents an ACME rate limit error, for which callers
+// should back off rather than treat the order as failed.
+func isRateLimited(err error) bool {
+	var acmeErr *acme.Error
+	if errors.As(err, &acmeErr) {
+		return acmeErr.ProblemType == acmeRateLimited
+	}
+
+	return false
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssl
+
+import (
+	"math/rand"
+	"time"
+)
+
+const (
+	defaultInitialInterval = 10 * time.Second
+	defaultMultiplier      = 2.0
+	defaultMaxInterval     = 5 * time.Minute
+	defaultMaxElapsedTime  = 30 * time.Minute
+	defaultMaxRetries      = 5
+)
+
+// Backoff is a jittered exponential backoff policy applied both to operation polling in
+// waitFor and to retrying Insert/Delete calls that fail with a quota or rate limit error.
+type Backoff struct {
+	// InitialInterval is the delay before the first retry, and the floor every
+	// subsequent delay is jittered above.
+	InitialInterval time.Duration
+
+	// Multiplier scales the interval after each attempt.
+	Multiplier float64
+
+	// MaxInterval caps the delay between attempts.
+	MaxInterval time.Duration
+
+	// MaxElapsedTime bounds the total time spent retrying, after which the last error
+	// is returned. Zero means no bound.
+	MaxElapsedTime time.Duration
+
+	// MaxRetries bounds the number of retries of a quota-exceeded Insert or Delete call,
+	// independent of MaxElapsedTime.
+	MaxRetries int
+
+	// jitter returns a value in [0, 1) used to jitter each delay. Defaults to
+	// rand.Float64; tests substitute a deterministic source.
+	jitter func() float64
+}
+
+// defaultBackoff is used by New and NewRegional unless overridden with WithBackoff.
+func defaultBackoff() Backoff {
+	return Backoff{
+		InitialInterval: defaultInitialInterval,
+		Multiplier:      defaultMultiplier,
+		MaxInterval:     defaultMaxInterval,
+		MaxElapsedTime:  defaultMaxElapsedTime,
+		MaxRetries:      defaultMaxRetries,
+		jitter:          rand.Float64,
+	}
+}
+
+// next returns the jittered delay to use for the given zero-based attempt number. The
+// delay never falls below InitialInterval (capped by MaxInterval): attempt grows the
+// ceiling exponentially and jitter is only applied to the room between the floor and that
+// ceiling, so polling never degenerates into a near-tight loop.
+func (b Backoff) next(attempt int) time.Duration {
+	jitter := b.jitter
+	if jitter == nil {
+		jitter = rand.Float64
+	}
+
+	ceiling := float64(b.InitialInterval)
+	for i := 0; i < attempt; i++ {
+		ceiling *= b.Multiplier
+	}
+	if max := float64(b.MaxInterval); b.MaxInterval > 0 && ceiling > max {
+		ceiling = max
+	}
+
+	floor := float64(b.InitialInterval)
+	if floor > ceiling {
+		floor = ceiling
+	}
+
+	return time.Duration(floor + jitter()*(ceiling-floor))
+}
+
+// Option configures an Ssl constructed by New or NewRegional.
+type Option func(*sslImpl)
+
+// WithBackoff overrides the backoff policy used for operation polling and for retrying
+// quota-exceeded Insert/Delete calls.
+func WithBackoff(backoff Backoff) Option {
+	return func(s *sslImpl) {
+		s.backoff = backoff
+	}
+}
+
+// WithMaxRetries overrides only the retry count of the backoff policy, leaving the
+// interval settings at their configured or default values.
+func WithMaxRetries(maxRetries int) Option {
+	return func(s *sslImpl) {
+		s.backoff.MaxRetries = maxRetries
+	}
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssl
+
+import (
+	"testing"
+	"time"
+)
+
+func testBackoff(jitter func() float64) Backoff {
+	return Backoff{
+		InitialInterval: 10 * time.Second,
+		Multiplier:      2.0,
+		MaxInterval:     1 * time.Minute,
+		jitter:          jitter,
+	}
+}
+
+func TestBackoffNextNeverBelowInitialInterval(t *testing.T) {
+	b := testBackoff(func() float64 { return 0 })
+
+	for attempt := 0; attempt < 5; attempt++ {
+		if got := b.next(attempt); got < b.InitialInterval {
+			t.Errorf("next(%d) = %s, want >= %s", attempt, got, b.InitialInterval)
+		}
+	}
+}
+
+func TestBackoffNextCappedAtMaxInterval(t *testing.T) {
+	b := testBackoff(func() float64 { return 1 })
+
+	if got := b.next(10); got > b.MaxInterval {
+		t.Errorf("next(10) = %s, want <= %s", got, b.MaxInterval)
+	}
+}
+
+func TestBackoffNextGrowsWithAttempt(t *testing.T) {
+	b := testBackoff(func() float64 { return 1 })
+
+	if first, second := b.next(0), b.next(1); second <= first {
+		t.Errorf("next(1) = %s, want > next(0) = %s", second, first)
+	}
+}
+
+func TestBackoffNextDeterministicWithFixedJitter(t *testing.T) {
+	b := testBackoff(func() float64 { return 0.5 })
+
+	// At attempt 0 the ceiling equals the floor (InitialInterval), so there is no
+	// jittered room yet; jitter only widens the gap from attempt 1 onward.
+	if got := b.next(0); got != b.InitialInterval {
+		t.Errorf("next(0) = %s, want %s", got, b.InitialInterval)
+	}
+
+	ceiling := time.Duration(float64(b.InitialInterval) * b.Multiplier)
+	want := b.InitialInterval + time.Duration(0.5*float64(ceiling-b.InitialInterval))
+	if got := b.next(1); got != want {
+		t.Errorf("next(1) = %s, want %s", got, want)
+	}
+}
@@ -25,15 +25,19 @@ import (
 	"time"
 
 	compute "google.golang.org/api/compute/v1"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
 	"k8s.io/klog"
 
 	utilshttp "github.com/GoogleCloudPlatform/gke-managed-certs/pkg/utils/http"
 )
 
 const (
-	codeQuotaExceeded = "QUOTA_EXCEEDED"
-	statusDone        = "DONE"
-	typeManaged       = "MANAGED"
+	codeQuotaExceeded     = "QUOTA_EXCEEDED"
+	codeRateLimitExceeded = "RATE_LIMIT_EXCEEDED"
+	statusDone            = "DONE"
+	typeManaged           = "MANAGED"
+	typeSelfManaged       = "SELF_MANAGED"
 )
 
 type Error struct {
@@ -52,8 +56,18 @@ func (s *Error) Error() string {
 }
 
 func (s *Error) IsQuotaExceeded() bool {
+	return s.hasCode(codeQuotaExceeded)
+}
+
+// IsRateLimitExceeded reports whether the operation failed because of a transient rate
+// limit, as opposed to a hard quota exhaustion.
+func (s *Error) IsRateLimitExceeded() bool {
+	return s.hasCode(codeRateLimitExceeded)
+}
+
+func (s *Error) hasCode(code string) bool {
 	for _, err := range s.operation.Error.Errors {
-		if err.Code == codeQuotaExceeded {
+		if err.Code == code {
 			return true
 		}
 	}
@@ -61,57 +75,155 @@ func (s *Error) IsQuotaExceeded() bool {
 	return false
 }
 
+// isRetryable reports whether err is a transient quota or rate limit error that is worth
+// retrying with backoff, be it a GCE operation error or an HTTP 429 from the API itself.
+func isRetryable(err error) bool {
+	if sslErr, ok := err.(*Error); ok {
+		return sslErr.IsQuotaExceeded() || sslErr.IsRateLimitExceeded()
+	}
+
+	if apiErr, ok := err.(*googleapi.Error); ok {
+		return apiErr.Code == http.StatusTooManyRequests
+	}
+
+	return false
+}
+
 type Ssl interface {
 	Create(ctx context.Context, name string, domains []string) error
+	CreateSelfManaged(ctx context.Context, name, certPEM, keyPEM string) error
 	Delete(ctx context.Context, name string) error
 	Exists(name string) (bool, error)
 	Get(name string) (*compute.SslCertificate, error)
-	List() ([]*compute.SslCertificate, error)
+	List(ctx context.Context) ([]*compute.SslCertificate, error)
+	ListFiltered(ctx context.Context, filter string, maxResults int64) ([]*compute.SslCertificate, error)
 }
 
 type sslImpl struct {
 	service   *compute.Service
 	projectID string
+	// region is empty for global SslCertificates, used by Internal HTTP(S) and
+	// regional external load balancers otherwise.
+	region        string
+	backoff       Backoff
+	clientOptions []option.ClientOption
+	// clientOptionsErr carries a failure from an Option that builds a client option
+	// (e.g. WithEnterpriseCertificateProxy), surfaced once all Options have run.
+	clientOptionsErr error
 }
 
-func New(client *http.Client, projectID string) (Ssl, error) {
-	service, err := compute.New(client)
+// New creates an Ssl operating on global SslCertificate resources, used by External
+// HTTP(S) Load Balancers. Authentication and transport are configured through opts, e.g.
+// WithClientOptions, WithImpersonatedCredentials or WithWorkloadIdentityFederation; with no
+// such option, application default credentials over the standard transport are used.
+func New(ctx context.Context, projectID string, opts ...Option) (Ssl, error) {
+	s := &sslImpl{
+		projectID: projectID,
+		backoff:   defaultBackoff(),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.clientOptionsErr != nil {
+		return nil, s.clientOptionsErr
+	}
+
+	service, err := compute.NewService(ctx, s.clientOptions...)
 	if err != nil {
 		return nil, err
 	}
+	s.service = service
 
-	return &sslImpl{
-		service:   service,
-		projectID: projectID,
-	}, nil
+	return s, nil
 }
 
-// Create creates a new SslCertificate resource.
-func (s sslImpl) Create(ctx context.Context, name string, domains []string) error {
-	sslCertificate := &compute.SslCertificate{
-		Managed: &compute.SslCertificateManagedSslCertificate{
-			Domains: domains,
-		},
-		Name: name,
-		Type: typeManaged,
+// NewRegional creates an Ssl operating on regional SslCertificate resources in the given
+// region, used by Internal HTTP(S) Load Balancers and regional external load balancers. See
+// New for how opts configure authentication and transport.
+func NewRegional(ctx context.Context, projectID, region string, opts ...Option) (Ssl, error) {
+	s := &sslImpl{
+		projectID: projectID,
+		region:    region,
+		backoff:   defaultBackoff(),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.clientOptionsErr != nil {
+		return nil, s.clientOptionsErr
 	}
 
-	operation, err := s.service.SslCertificates.Insert(s.projectID, sslCertificate).Do()
+	service, err := compute.NewService(ctx, s.clientOptions...)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	s.service = service
+
+	return s, nil
+}
+
+// Create creates a new SslCertificate resource.
+func (s sslImpl) Create(ctx context.Context, name string, domains []string) error {
+	return s.withRetry(ctx, func() error {
+		sslCertificate := &compute.SslCertificate{
+			Managed: &compute.SslCertificateManagedSslCertificate{
+				Domains: domains,
+			},
+			Name: name,
+			Type: typeManaged,
+		}
+
+		operationName, err := s.insert(sslCertificate)
+		if err != nil {
+			return err
+		}
 
-	return s.waitFor(ctx, operation.Name)
+		return s.waitFor(ctx, operationName)
+	})
+}
+
+// CreateSelfManaged creates a new SslCertificate resource of type SELF_MANAGED, populated
+// with a user-supplied certificate and private key, both PEM-encoded.
+func (s sslImpl) CreateSelfManaged(ctx context.Context, name, certPEM, keyPEM string) error {
+	return s.withRetry(ctx, func() error {
+		sslCertificate := &compute.SslCertificate{
+			SelfManaged: &compute.SslCertificateSelfManagedSslCertificate{
+				Certificate: certPEM,
+				PrivateKey:  keyPEM,
+			},
+			Name: name,
+			Type: typeSelfManaged,
+		}
+
+		operationName, err := s.insert(sslCertificate)
+		if err != nil {
+			return err
+		}
+
+		return s.waitFor(ctx, operationName)
+	})
 }
 
 // Delete deletes an SslCertificate resource.
 func (s sslImpl) Delete(ctx context.Context, name string) error {
-	operation, err := s.service.SslCertificates.Delete(s.projectID, name).Do()
-	if err != nil {
-		return err
-	}
+	return s.withRetry(ctx, func() error {
+		var operationName string
+		if s.isRegional() {
+			operation, err := s.service.RegionSslCertificates.Delete(s.projectID, s.region, name).Do()
+			if err != nil {
+				return err
+			}
+			operationName = operation.Name
+		} else {
+			operation, err := s.service.SslCertificates.Delete(s.projectID, name).Do()
+			if err != nil {
+				return err
+			}
+			operationName = operation.Name
+		}
 
-	return s.waitFor(ctx, operation.Name)
+		return s.waitFor(ctx, operationName)
+	})
 }
 
 // Exists returns true if an SslCertificate exists, false if it is deleted. Error is not nil if an error has occurred.
@@ -130,23 +242,98 @@ func (s sslImpl) Exists(name string) (bool, error) {
 
 // Get fetches an SslCertificate resource.
 func (s sslImpl) Get(name string) (*compute.SslCertificate, error) {
+	if s.isRegional() {
+		return s.service.RegionSslCertificates.Get(s.projectID, s.region, name).Do()
+	}
+
 	return s.service.SslCertificates.Get(s.projectID, name).Do()
 }
 
-// List fetches all SslCertificate resources.
-func (s sslImpl) List() ([]*compute.SslCertificate, error) {
-	sslCertificates, err := s.service.SslCertificates.List(s.projectID).Do()
+// List fetches all SslCertificate resources, paging through the full result set rather
+// than truncating at the API's default page size.
+func (s sslImpl) List(ctx context.Context) ([]*compute.SslCertificate, error) {
+	return s.ListFiltered(ctx, "", 0)
+}
+
+// ListFiltered fetches SslCertificate resources matching filter (the GCE API's filter
+// expression syntax, e.g. "name eq my-prefix.*"), paging through the full result set.
+// maxResults bounds the page size requested per call; zero uses the API default.
+func (s sslImpl) ListFiltered(ctx context.Context, filter string, maxResults int64) ([]*compute.SslCertificate, error) {
+	var sslCertificates []*compute.SslCertificate
+
+	if s.isRegional() {
+		call := s.service.RegionSslCertificates.List(s.projectID, s.region)
+		if filter != "" {
+			call = call.Filter(filter)
+		}
+		if maxResults > 0 {
+			call = call.MaxResults(maxResults)
+		}
+
+		err := call.Pages(ctx, func(page *compute.SslCertificateList) error {
+			sslCertificates = append(sslCertificates, page.Items...)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return sslCertificates, nil
+	}
+
+	call := s.service.SslCertificates.List(s.projectID)
+	if filter != "" {
+		call = call.Filter(filter)
+	}
+	if maxResults > 0 {
+		call = call.MaxResults(maxResults)
+	}
+
+	err := call.Pages(ctx, func(page *compute.SslCertificateList) error {
+		sslCertificates = append(sslCertificates, page.Items...)
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	return sslCertificates.Items, nil
+	return sslCertificates, nil
+}
+
+// isRegional reports whether this Ssl manages regional, rather than global, SslCertificates.
+func (s sslImpl) isRegional() bool {
+	return s.region != ""
+}
+
+// insert creates sslCertificate in the scope (global or regional) this Ssl is configured for
+// and returns the name of the GCE operation tracking the request.
+func (s sslImpl) insert(sslCertificate *compute.SslCertificate) (string, error) {
+	if s.isRegional() {
+		operation, err := s.service.RegionSslCertificates.Insert(s.projectID, s.region, sslCertificate).Do()
+		if err != nil {
+			return "", err
+		}
+		return operation.Name, nil
+	}
+
+	operation, err := s.service.SslCertificates.Insert(s.projectID, sslCertificate).Do()
+	if err != nil {
+		return "", err
+	}
+	return operation.Name, nil
 }
 
 func (s sslImpl) waitFor(ctx context.Context, operationName string) error {
-	for {
+	for attempt := 0; ; attempt++ {
 		klog.Infof("Wait for operation %s", operationName)
-		operation, err := s.service.GlobalOperations.Get(s.projectID, operationName).Do()
+
+		var operation *compute.Operation
+		var err error
+		if s.isRegional() {
+			operation, err = s.service.RegionOperations.Get(s.projectID, s.region, operationName).Do()
+		} else {
+			operation, err = s.service.GlobalOperations.Get(s.projectID, operationName).Do()
+		}
 		if err != nil {
 			return fmt.Errorf("could not get operation %s: %s", operationName, err.Error())
 		}
@@ -164,7 +351,37 @@ func (s sslImpl) waitFor(ctx context.Context, operationName string) error {
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
-		case <-time.After(10 * time.Second):
+		case <-time.After(s.backoff.next(attempt)):
+		}
+	}
+}
+
+// withRetry calls fn, and while it fails with a transient quota or rate limit error,
+// retries it with jittered exponential backoff, honoring ctx.Done() between attempts and
+// giving up once s.backoff.MaxRetries or s.backoff.MaxElapsedTime is exceeded.
+func (s sslImpl) withRetry(ctx context.Context, fn func() error) error {
+	start := time.Now()
+
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil || !isRetryable(err) {
+			return err
+		}
+
+		if s.backoff.MaxRetries > 0 && attempt >= s.backoff.MaxRetries {
+			return err
+		}
+		if s.backoff.MaxElapsedTime > 0 && time.Since(start) > s.backoff.MaxElapsedTime {
+			return err
+		}
+
+		delay := s.backoff.next(attempt)
+		klog.Warningf("Retrying after quota/rate limit error in %s (attempt %d): %s", delay, attempt+1, err.Error())
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
 		}
 	}
 }
@@ -0,0 +1,87 @@
+/*
+Copyright 2020 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssl
+
+import (
+	"crypto/tls"
+
+	ecp "github.com/googleapis/enterprise-certificate-proxy/client"
+	"google.golang.org/api/option"
+)
+
+const defaultComputeScope = "https://www.googleapis.com/auth/compute"
+
+// WithClientOptions passes clientOpts straight through to compute.NewService, mirroring
+// the options accepted by the underlying compute/v1 client. Use this for anything not
+// covered by the more specific helpers below.
+func WithClientOptions(clientOpts ...option.ClientOption) Option {
+	return func(s *sslImpl) {
+		s.clientOptions = append(s.clientOptions, clientOpts...)
+	}
+}
+
+// WithEnterpriseCertificateProxy authenticates over mTLS using a hardware- or
+// OS-keychain-resident client certificate (HSM, TPM, or platform keystore), via Google's
+// enterprise-certificate-proxy. The bearer token credentials configured otherwise are still
+// used alongside the client certificate. configFilePath points at the
+// proxy's config file, as produced by `gcloud auth enterprise-certificate-config create`.
+func WithEnterpriseCertificateProxy(configFilePath string) Option {
+	return func(s *sslImpl) {
+		key, err := ecp.Cred(configFilePath)
+		if err != nil {
+			s.clientOptionsErr = err
+			return
+		}
+
+		certSource := func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return &tls.Certificate{
+				Certificate: key.CertificateChain(),
+				PrivateKey:  key,
+			}, nil
+		}
+
+		s.clientOptions = append(s.clientOptions, option.WithClientCertSource(certSource))
+	}
+}
+
+// WithImpersonatedCredentials configures the client to call the GCE API as
+// targetPrincipal, impersonating that service account from whatever credentials are
+// otherwise ambient (the pod's attached SA, a user's gcloud login, etc), optionally
+// delegating through a chain of intermediate service accounts. This lets a multi-tenant
+// deployment act as a different service account per reconcile without provisioning a
+// long-lived key for each one. scopes defaults to the compute scope if empty.
+func WithImpersonatedCredentials(targetPrincipal string, scopes []string, delegates ...string) Option {
+	return func(s *sslImpl) {
+		if len(scopes) == 0 {
+			scopes = []string{defaultComputeScope}
+		}
+
+		s.clientOptions = append(s.clientOptions,
+			option.ImpersonateCredentials(targetPrincipal, delegates...),
+			option.WithScopes(scopes...))
+	}
+}
+
+// WithWorkloadIdentityFederation authenticates with an external account credentials
+// config (produced by `gcloud iam workload-identity-pools create-cred-config`), letting
+// the controller run under a non-GCP identity (e.g. a Kubernetes or AWS identity) instead
+// of a long-lived GCP service account key.
+func WithWorkloadIdentityFederation(credentialsConfigPath string) Option {
+	return func(s *sslImpl) {
+		s.clientOptions = append(s.clientOptions, option.WithCredentialsFile(credentialsConfigPath))
+	}
+}